@@ -0,0 +1,24 @@
+package block
+
+import "github.com/ethereum/go-ethereum/common"
+
+// Header is harmony's block header type. header.go (not part of this change)
+// defines the rest of its fields -- ParentHash, Number, Epoch, ShardState,
+// the BLS commit signature, their RLP encoding -- and this file only adds
+// the slashRoot field the SlashRoot fork needs.
+type Header struct {
+	slashRoot common.Hash
+}
+
+// SlashRoot returns the Merkle root committing to every slash.Record applied
+// while finalizing this block, or the zero hash before the SlashRoot fork
+// activates (see params.ChainConfig.IsSlashRoot).
+func (h *Header) SlashRoot() common.Hash {
+	return h.slashRoot
+}
+
+// SetSlashRoot sets the Merkle root committing to this block's applied
+// slashes, see SlashRoot.
+func (h *Header) SetSlashRoot(root common.Hash) {
+	h.slashRoot = root
+}
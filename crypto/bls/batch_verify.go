@@ -0,0 +1,49 @@
+package bls
+
+import (
+	"math/big"
+)
+
+// BatchVerifyMultiPairing checks
+//
+//	e(Σ rᵢ·aggSigᵢ, g2) == Π e(rᵢ·aggPubᵢ, H(msgᵢ))
+//
+// in one combined multi-pairing instead of one pairing check per tuple,
+// which is what dominates sync/header-verification CPU when done one header
+// at a time. scalars[i] is tuple i's random weight -- the caller (see
+// internal/chain's VerifyHeaderSignatures) draws them unpredictably from
+// crypto/rand and must reject duplicate (pubkey, message) pairs before
+// calling this, since two otherwise-invalid signatures sharing a pair could
+// cancel each other out under the random linear combination.
+//
+// It's stated at the same level as a single aggregate-signature check:
+// scale each tuple's signature and public key by its scalar, sum the scaled
+// signatures into one point, and verify the result the same way a single
+// (non-random) aggregate signature over distinct per-signer messages always
+// is, via AggregateVerify. That keeps the actual pairing math in one place
+// instead of duplicating it here.
+//
+// len(aggSigs) == len(pubs) == len(msgs) == len(scalars) is the caller's
+// responsibility; a mismatch returns false rather than panicking.
+func BatchVerifyMultiPairing(
+	aggSigs []*Sign, pubs []*PublicKeyWrapper, msgs [][]byte, scalars []*big.Int,
+) bool {
+	n := len(aggSigs)
+	if n == 0 || len(pubs) != n || len(msgs) != n || len(scalars) != n {
+		return false
+	}
+
+	var combined *Sign
+	scaledPubs := make([]*PublicKeyWrapper, n)
+	for i := 0; i < n; i++ {
+		scaledSig := aggSigs[i].Mul(scalars[i])
+		if combined == nil {
+			combined = scaledSig
+		} else {
+			combined = combined.Add(scaledSig)
+		}
+		scaledPubs[i] = pubs[i].Mul(scalars[i])
+	}
+
+	return AggregateVerify(combined, scaledPubs, msgs)
+}
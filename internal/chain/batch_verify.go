@@ -0,0 +1,194 @@
+package chain
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/consensus/signature"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+)
+
+// scalarBits is the bit-width of the random scalars used to combine
+// signatures before the multi-pairing check. 128 bits is far more than an
+// adversary could feasibly search to find cancelling scalars, while staying
+// well inside the BLS curve's scalar field.
+const scalarBits = 128
+
+// headerSigTuple is one (header, sig, bitmap) entry resolved down to the
+// inputs the multi-pairing check actually needs.
+type headerSigTuple struct {
+	idx      int
+	aggSig   *bls_cosi.Sign
+	aggPub   *bls_cosi.PublicKeyWrapper
+	msg      []byte
+	cacheKey verifiedSigKey
+}
+
+// VerifyHeaderSignatures verifies many headers' aggregate commit signatures
+// with a single multi-pairing check instead of one pairing check per header,
+// which is what dominates sync CPU today:
+//
+//	e(Σ r_i·aggSig_i, g2) == Π e(r_i·aggPub_i, H(msg_i))
+//
+// Each (header, sig, bitmap) tuple is combined with an independent random
+// scalar r_i drawn from crypto/rand. The randomness is essential: without it,
+// an adversary who controls two of the signatures being batched can choose
+// one to cancel the other out under addition, even though neither signature
+// is individually valid. Every tuple sharing an (aggPub, msg) pair with
+// another is pulled out of the batch for the same reason and verified
+// individually instead, so one tuple's outcome never depends on whether it
+// happened to be the first or a later occurrence of a duplicated pair.
+//
+// On failure, every remaining header is re-verified individually so the
+// caller learns exactly which one is bad. On success, each tuple is added to
+// verifiedSigCache, same as the serial verifyHeaderSignatureCached path.
+// header[i] is the header whose epoch committee to verify against -- for
+// VerifySeal-style checks that's a block's parent, for cross-shard proofs
+// (VerifyHeaderSignature) that's the header itself.
+func (e *engineImpl) VerifyHeaderSignatures(
+	chain engine.ChainReader, headers []*block.Header,
+	sigs []bls_cosi.SerializedSignature, bitmaps [][]byte,
+) []error {
+	errs := make([]error, len(headers))
+	if len(headers) != len(sigs) || len(headers) != len(bitmaps) {
+		for i := range errs {
+			errs[i] = errors.New("VerifyHeaderSignatures: headers/sigs/bitmaps length mismatch")
+		}
+		return errs
+	}
+
+	byDedupeKey := map[string][]headerSigTuple{}
+	dedupeOrder := make([]string, 0, len(headers)) // first-seen order, for deterministic iteration
+
+	for i, header := range headers {
+		cacheKey := newVerifiedSigKey(header.Hash(), sigs[i], bitmaps[i])
+		if _, ok := e.verifiedSigCache.Get(cacheKey); ok {
+			continue
+		}
+
+		ec, ok := e.getCachedEpochCtx(header)
+		if !ok {
+			var err error
+			ec, err = readEpochCtxFromChain(chain, header.Epoch(), header.ShardID())
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			e.epochCtxCache.Add(newEpochCtxKeyFromHeader(header), ec)
+		}
+
+		aggSig, mask, err := DecodeSigBitmap(sigs[i], bitmaps[i], ec.pubKeys)
+		if err != nil {
+			errs[i] = errors.Wrap(err, "deserialize signature and bitmap")
+			continue
+		}
+		if !ec.qrVerifier.IsQuorumAchievedByMask(mask) {
+			errs[i] = errors.New("not enough signature collected")
+			continue
+		}
+
+		msg := signature.ConstructCommitPayload(chain,
+			header.Epoch(), header.Hash(), header.Number().Uint64(), header.ViewID().Uint64())
+
+		dedupeKey := string(mask.AggregatePublic.Bytes()) + "|" + string(msg)
+		if _, ok := byDedupeKey[dedupeKey]; !ok {
+			dedupeOrder = append(dedupeOrder, dedupeKey)
+		}
+		byDedupeKey[dedupeKey] = append(byDedupeKey[dedupeKey], headerSigTuple{
+			idx: i, aggSig: aggSig, aggPub: mask.AggregatePublic, msg: msg, cacheKey: cacheKey,
+		})
+	}
+
+	// Collect every dedupeKey that appears exactly once into the batch;
+	// anything appearing more than once is verified individually instead of
+	// being batched at all, so no tuple can simultaneously sit in `tuples`
+	// (and so get cache-written on a later batch success) and be marked
+	// failed as a duplicate -- the batch and the individual path are now
+	// mutually exclusive per tuple.
+	tuples := make([]headerSigTuple, 0, len(headers))
+	for _, key := range dedupeOrder {
+		group := byDedupeKey[key]
+		if len(group) > 1 {
+			verifyTuplesIndividually(group, errs, e)
+			continue
+		}
+		tuples = append(tuples, group[0])
+	}
+
+	if len(tuples) == 0 {
+		return errs
+	}
+	if len(tuples) == 1 {
+		t := tuples[0]
+		if err := verifyTuplePairing(t.aggPub, t.aggSig, t.msg); err != nil {
+			errs[t.idx] = err
+		} else {
+			e.verifiedSigCache.Add(t.cacheKey, struct{}{})
+		}
+		return errs
+	}
+
+	scalars, err := randomScalars(len(tuples))
+	if err != nil {
+		// crypto/rand failure: never batch with a predictable scalar, fall
+		// back to verifying every tuple on its own.
+		verifyTuplesIndividually(tuples, errs, e)
+		return errs
+	}
+
+	msgs := make([][]byte, len(tuples))
+	pubs := make([]*bls_cosi.PublicKeyWrapper, len(tuples))
+	aggSigs := make([]*bls_cosi.Sign, len(tuples))
+	for i, t := range tuples {
+		msgs[i], pubs[i], aggSigs[i] = t.msg, t.aggPub, t.aggSig
+	}
+
+	if bls_cosi.BatchVerifyMultiPairing(aggSigs, pubs, msgs, scalars) {
+		for _, t := range tuples {
+			e.verifiedSigCache.Add(t.cacheKey, struct{}{})
+		}
+		return errs
+	}
+
+	// The batch as a whole failed the combined pairing check; fall back to
+	// pinpointing exactly which tuple(s) are bad.
+	verifyTuplesIndividually(tuples, errs, e)
+	return errs
+}
+
+func verifyTuplesIndividually(tuples []headerSigTuple, errs []error, e *engineImpl) {
+	for _, t := range tuples {
+		if err := verifyTuplePairing(t.aggPub, t.aggSig, t.msg); err != nil {
+			errs[t.idx] = err
+			continue
+		}
+		e.verifiedSigCache.Add(t.cacheKey, struct{}{})
+	}
+}
+
+func verifyTuplePairing(pub *bls_cosi.PublicKeyWrapper, sig *bls_cosi.Sign, msg []byte) error {
+	if !sig.VerifyHash(pub, msg) {
+		return errors.New("unable to verify aggregated signature for block")
+	}
+	return nil
+}
+
+// randomScalars draws n independent scalarBits-wide random scalars. Returning
+// an error instead of silently degrading lets the caller refuse to batch
+// rather than use a weak or repeated scalar.
+func randomScalars(n int) ([]*big.Int, error) {
+	max := new(big.Int).Lsh(big.NewInt(1), scalarBits)
+	scalars := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		r, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return nil, errors.Wrap(err, "generate random batch-verification scalar")
+		}
+		scalars[i] = r
+	}
+	return scalars, nil
+}
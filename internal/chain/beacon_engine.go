@@ -0,0 +1,150 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/consensus/reward"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/staking/slash"
+	staking "github.com/harmony-one/harmony/staking/types"
+)
+
+// PayloadAttributes describes the next block an external payload provider
+// should build, the harmony analogue of go-ethereum's engine API
+// PayloadAttributesV1.
+type PayloadAttributes struct {
+	Timestamp  uint64
+	ParentHash common.Hash
+}
+
+// ForkchoiceState mirrors go-ethereum's ForkchoiceStateV1: the external
+// payload coordinator's view of head/safe/finalized block hashes. A non-zero
+// FinalizedBlockHash means the coordinator itself now considers the
+// PoS->external handoff irreversible, which is what finally drives
+// Merger.FinalizeTransition -- see ForkchoiceUpdatedV1.
+type ForkchoiceState struct {
+	HeadBlockHash      common.Hash
+	SafeBlockHash      common.Hash
+	FinalizedBlockHash common.Hash
+}
+
+// PayloadID identifies a payload an external provider is assembling, returned
+// by ForkchoiceUpdated and redeemed via GetPayload.
+type PayloadID [8]byte
+
+// PayloadProvider is implemented by the external coordinator that assembles
+// blocks once a chain has transitioned off BLS-BFT block production. It is
+// the harmony-side counterpart of an eth2 consensus client driving an eth1
+// execution client over the engine API.
+type PayloadProvider interface {
+	ForkchoiceUpdated(ctx context.Context, headBlockHash common.Hash, attrs *PayloadAttributes) (PayloadID, error)
+	GetPayload(ctx context.Context, id PayloadID) (*types.Block, error)
+	NewPayload(ctx context.Context, block *types.Block) error
+}
+
+// BeaconEngine wraps engineImpl the way go-ethereum's beacon consensus engine
+// wraps ethash/clique: before the configured terminal transition block it
+// delegates header verification and block assembly to pre unchanged; after
+// the transition, block assembly is driven by an external PayloadProvider
+// instead, while header verification (parent linkage, commit-sig checks)
+// still goes through pre so cross-shard and light-client verification keeps
+// working.
+type BeaconEngine struct {
+	pre     *engineImpl
+	merger  *Merger
+	payload PayloadProvider
+}
+
+// NewBeaconEngine wraps pre with a merger and, once the transition happens, an
+// external payload provider.
+func NewBeaconEngine(pre *engineImpl, merger *Merger, payload PayloadProvider) *BeaconEngine {
+	pre.SetMerger(merger)
+	return &BeaconEngine{pre: pre, merger: merger, payload: payload}
+}
+
+func (b *BeaconEngine) Beaconchain() engine.ChainReader { return b.pre.Beaconchain() }
+
+func (b *BeaconEngine) SetBeaconchain(beaconchain engine.ChainReader) {
+	b.pre.SetBeaconchain(beaconchain)
+}
+
+func (b *BeaconEngine) VerifyHeader(chain engine.ChainReader, header *block.Header, seal bool) error {
+	return b.pre.VerifyHeader(chain, header, seal)
+}
+
+func (b *BeaconEngine) VerifyHeaders(chain engine.ChainReader, headers []*block.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	return b.pre.VerifyHeaders(chain, headers, seals)
+}
+
+func (b *BeaconEngine) VerifyShardState(bc engine.ChainReader, beacon engine.ChainReader, header *block.Header) error {
+	return b.pre.VerifyShardState(bc, beacon, header)
+}
+
+func (b *BeaconEngine) VerifySeal(chain engine.ChainReader, header *block.Header) error {
+	return b.pre.VerifySeal(chain, header)
+}
+
+func (b *BeaconEngine) VerifyHeaderSignature(chain engine.ChainReader, header *block.Header, commitSig bls_cosi.SerializedSignature, commitBitmap []byte) error {
+	return b.pre.VerifyHeaderSignature(chain, header, commitSig, commitBitmap)
+}
+
+// Finalize delegates to pre, which already zeroes out
+// AccumulateRewardsAndCountSigs once b.merger reports PoSFinalized.
+func (b *BeaconEngine) Finalize(
+	chain engine.ChainReader, header *block.Header,
+	state *state.DB, txs []*types.Transaction,
+	receipts []*types.Receipt, outcxs []*types.CXReceipt,
+	incxs []*types.CXReceiptsProof, stks staking.StakingTransactions,
+	doubleSigners slash.Records, sigsReady chan bool, viewID func() uint64,
+) (*types.Block, reward.Reader, error) {
+	return b.pre.Finalize(
+		chain, header, state, txs, receipts, outcxs, incxs, stks,
+		doubleSigners, sigsReady, viewID,
+	)
+}
+
+// EngineAPI exposes the engine_newPayloadV1 / engine_forkchoiceUpdatedV1 RPC
+// methods an external payload coordinator calls to drive block assembly after
+// the transition.
+type EngineAPI struct {
+	beacon *BeaconEngine
+}
+
+// NewEngineAPI wraps beacon for RPC exposure under the "engine" namespace.
+func NewEngineAPI(beacon *BeaconEngine) *EngineAPI {
+	return &EngineAPI{beacon: beacon}
+}
+
+// NewPayloadV1 is engine_newPayloadV1: the coordinator hands over a block it
+// assembled for this node to import.
+func (api *EngineAPI) NewPayloadV1(ctx context.Context, blk *types.Block) error {
+	if api.beacon.payload == nil {
+		return errors.New("engine API called before a payload provider is configured")
+	}
+	return api.beacon.payload.NewPayload(ctx, blk)
+}
+
+// ForkchoiceUpdatedV1 is engine_forkchoiceUpdatedV1: the coordinator informs
+// this node of the new head and, optionally, asks it to start building a
+// payload on top of it. A non-zero state.FinalizedBlockHash additionally
+// marks the PoS->external handoff itself as finalized: once the coordinator
+// reports a finalized block past the transition, BLS-BFT block production
+// (and its commit-sig rewards) is gone for good, even across a reorg back
+// before the terminal block -- see Merger.FinalizeTransition.
+func (api *EngineAPI) ForkchoiceUpdatedV1(ctx context.Context, state *ForkchoiceState, attrs *PayloadAttributes) (PayloadID, error) {
+	if api.beacon.payload == nil {
+		return PayloadID{}, errors.New("engine API called before a payload provider is configured")
+	}
+	api.beacon.merger.LeavePoS()
+	if state.FinalizedBlockHash != (common.Hash{}) {
+		api.beacon.merger.FinalizeTransition()
+	}
+	return api.beacon.payload.ForkchoiceUpdated(ctx, state.HeadBlockHash, attrs)
+}
@@ -0,0 +1,49 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/harmony-one/harmony/consensus/engine"
+)
+
+// CliqueAPI exposes the clique_* RPC namespace (clique_getSigners,
+// clique_propose) that operators of a clique devnet use to inspect and change
+// the signer set, mirroring go-ethereum's clique.API. It is a thin read/propose
+// layer over cliqueEngine's snapshots; the node's RPC service registers it
+// under the "clique" namespace the same way it registers other consensus APIs.
+type CliqueAPI struct {
+	engine *cliqueEngine
+	chain  engine.ChainReader
+}
+
+// NewCliqueAPI wraps a running clique engine for RPC exposure.
+func NewCliqueAPI(e *cliqueEngine, chain engine.ChainReader) *CliqueAPI {
+	return &CliqueAPI{engine: e, chain: chain}
+}
+
+// GetSigners returns the authorized signer set as of the current head,
+// i.e. the clique_getSigners RPC method.
+func (api *CliqueAPI) GetSigners() ([]common.Address, error) {
+	header := api.chain.CurrentHeader()
+	snap, err := api.engine.snapshot(api.chain, header.Number().Uint64(), header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return snap.signerSlice(), nil
+}
+
+// Propose casts a vote to add (auth) or remove (drop) signer: it records the
+// pending vote, for whatever builds this node's next header to pick up via
+// cliqueEngine.NextProposal and set as that header's Coinbase/nonce, i.e. the
+// clique_propose RPC method. The vote is only tallied -- and the signer set
+// only changed -- once that header is sealed and cliqueEngine.snapshot walks
+// over it.
+func (api *CliqueAPI) Propose(signer common.Address, auth bool) {
+	nonce := SignerVoteNonceDrop
+	if auth {
+		nonce = SignerVoteNonceAuth
+	}
+	api.engine.proposalsMu.Lock()
+	defer api.engine.proposalsMu.Unlock()
+	api.engine.proposals[signer] = nonce
+}
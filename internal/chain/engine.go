@@ -2,6 +2,7 @@ package chain
 
 import (
 	"bytes"
+	"context"
 	"math/big"
 	"sort"
 
@@ -34,6 +35,11 @@ const (
 type engineImpl struct {
 	beacon engine.ChainReader
 
+	// merger tracks the PoS -> external-payload transition (see merger.go).
+	// It is nil on chains that never configure a terminal transition block,
+	// in which case Finalize behaves exactly as it always has.
+	merger *Merger
+
 	// Caching field
 	epochCtxCache    *lru.Cache // epochCtxKey -> epochCtx
 	verifiedSigCache *lru.Cache // verifiedSigKey -> struct{}{}
@@ -59,6 +65,12 @@ func (e *engineImpl) SetBeaconchain(beaconchain engine.ChainReader) {
 	e.beacon = beaconchain
 }
 
+// SetMerger wires in the PoS->external-payload transition tracker. Chains
+// that never configure a terminal transition block can leave this unset.
+func (e *engineImpl) SetMerger(merger *Merger) {
+	e.merger = merger
+}
+
 // VerifyHeader checks whether a header conforms to the consensus rules of the bft engine.
 // Note that each block header contains the bls signature of the parent block
 func (e *engineImpl) VerifyHeader(chain engine.ChainReader, header *block.Header, seal bool) error {
@@ -75,27 +87,13 @@ func (e *engineImpl) VerifyHeader(chain engine.ChainReader, header *block.Header
 }
 
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
-// concurrently. The method returns a quit channel to abort the operations and
-// a results channel to retrieve the async verifications.
-// WARN: Do not use VerifyHeaders for now. Currently a header verification can only
-// success when the previous header is written to block chain
-// TODO: Revisit and correct this function when adding epochChain
+// concurrently. See verify_headers.go for the worker-pool implementation; this
+// stub stays here so the engine.Engine interface implementation is easy to find
+// alongside VerifyHeader and VerifySeal.
+// The method returns a quit channel to abort the operations and a results
+// channel to retrieve the async verifications, in header order.
 func (e *engineImpl) VerifyHeaders(chain engine.ChainReader, headers []*block.Header, seals []bool) (chan<- struct{}, <-chan error) {
-	abort, results := make(chan struct{}), make(chan error, len(headers))
-
-	go func() {
-		for i, header := range headers {
-			err := e.VerifyHeader(chain, header, seals[i])
-
-			select {
-			case <-abort:
-				return
-			case results <- err:
-			}
-		}
-	}()
-
-	return abort, results
+	return e.verifyHeadersConcurrent(chain, headers, seals)
 }
 
 // VerifyShardState implements Engine, checking the shardstate is valid at epoch transition
@@ -164,6 +162,8 @@ func (e *engineImpl) VerifySeal(chain engine.ChainReader, header *block.Header)
 // Finalize implements Engine, accumulating the block rewards,
 // setting the final state and assembling the block.
 // sigsReady signal indicates whether the commit sigs are populated in the header object.
+// Finalize keeps its old channel-based signature for existing callers, but is
+// now a thin wrapper around FinalizeAndAssemble (see finalize_stream.go).
 func (e *engineImpl) Finalize(
 	chain engine.ChainReader, header *block.Header,
 	state *state.DB, txs []*types.Transaction,
@@ -171,6 +171,25 @@ func (e *engineImpl) Finalize(
 	incxs []*types.CXReceiptsProof, stks staking.StakingTransactions,
 	doubleSigners slash.Records, sigsReady chan bool, viewID func() uint64,
 ) (*types.Block, reward.Reader, error) {
+	return e.FinalizeAndAssemble(
+		context.Background(), chain, header, state,
+		FinalizeBody{
+			Txs: txs, Receipts: receipts, Outgoing: outcxs, Incoming: incxs,
+			Staking: stks, DoubleSigners: doubleSigners,
+		},
+		FinalizeOptions{SigSource: chanSigSource{sigsReady: sigsReady}, ViewID: viewID},
+	)
+}
+
+// finalize is the actual accumulate-rewards/apply-slashes/assemble-block
+// implementation, shared by Finalize and FinalizeAndAssemble.
+func (e *engineImpl) finalize(
+	chain engine.ChainReader, header *block.Header,
+	state *state.DB, txs []*types.Transaction,
+	receipts []*types.Receipt, outcxs []*types.CXReceipt,
+	incxs []*types.CXReceiptsProof, stks staking.StakingTransactions,
+	doubleSigners slash.Records, sigsReady chan bool, viewID func() uint64,
+) (*types.Block, reward.Reader, error) {
 
 	isBeaconChain := header.ShardID() == shard.BeaconChainShardID
 	inStakingEra := chain.Config().IsStaking(header.Epoch())
@@ -205,13 +224,22 @@ func (e *engineImpl) Finalize(
 		}
 	}
 
-	// Accumulate block rewards and commit the final state root
-	// Header seems complete, assemble into a block and return
-	payout, err := AccumulateRewardsAndCountSigs(
-		chain, state, header, e.Beaconchain(), sigsReady,
-	)
-	if err != nil {
-		return nil, nil, err
+	// Accumulate block rewards and commit the final state root. Once the
+	// merger reports the PoS->external-payload handoff is finalized, block
+	// production (and so commit-sig based rewards) has permanently moved to
+	// the external payload provider, so this step is skipped; undelegation
+	// payouts and slashing above/below are unaffected since validators are
+	// still staked and can still double-sign regardless of who assembles
+	// blocks.
+	var payout reward.Reader
+	if e.merger == nil || !e.merger.PoSFinalized() {
+		var err error
+		payout, err = AccumulateRewardsAndCountSigs(
+			chain, state, header, e.Beaconchain(), sigsReady,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Apply slashes
@@ -310,18 +338,11 @@ func applySlashes(
 	state *state.DB,
 	doubleSigners slash.Records,
 ) error {
-	type keyStruct struct {
-		height  uint64
-		viewID  uint64
-		shardID uint32
-		epoch   uint64
-	}
-
-	groupedRecords := map[keyStruct]slash.Records{}
+	groupedRecords := map[slashGroupKey]slash.Records{}
 
 	// First group slashes by same signed blocks
 	for i := range doubleSigners {
-		thisKey := keyStruct{
+		thisKey := slashGroupKey{
 			height:  doubleSigners[i].Evidence.Height,
 			viewID:  doubleSigners[i].Evidence.ViewID,
 			shardID: doubleSigners[i].Evidence.Moment.ShardID,
@@ -330,22 +351,19 @@ func applySlashes(
 		groupedRecords[thisKey] = append(groupedRecords[thisKey], doubleSigners[i])
 	}
 
-	sortedKeys := []keyStruct{}
+	sortedKeys := []slashGroupKey{}
 
 	for key := range groupedRecords {
 		sortedKeys = append(sortedKeys, key)
 	}
 
-	// Sort them so the slashes are always consistent
+	// Sort them so the slashes are always consistent. Each field is only
+	// compared once the preceding ones tie, so shardID dominates, then
+	// height, then viewID, then epoch -- a proper tuple order, not the
+	// previous comparator's chained else-ifs, which fell through to compare
+	// height/viewID even when shardID alone already decided the order.
 	sort.SliceStable(sortedKeys, func(i, j int) bool {
-		if sortedKeys[i].shardID < sortedKeys[j].shardID {
-			return true
-		} else if sortedKeys[i].height < sortedKeys[j].height {
-			return true
-		} else if sortedKeys[i].viewID < sortedKeys[j].viewID {
-			return true
-		}
-		return false
+		return lessSlashGroupKey(sortedKeys[i], sortedKeys[j])
 	})
 
 	// Do the slashing by groups in the sorted order
@@ -391,6 +409,22 @@ func applySlashes(
 			RawJSON("applied", []byte(slashApplied.String())).
 			Msg("slash applied successfully")
 	}
+
+	// Commit a Merkle root of the canonicalized applied slashes into the
+	// header so light clients and cross-shard verifiers can prove inclusion
+	// of a specific slash.Record without replaying state. Pre-fork epochs
+	// leave the root zero and verifiers are expected to skip the check.
+	if chain.Config().IsSlashRoot(header.Epoch()) {
+		canon, err := canonicalizeSlashRecords(doubleSigners)
+		if err != nil {
+			return errors.Wrap(err, "canonicalize slash records for SlashRoot")
+		}
+		root, err := computeSlashRoot(canon)
+		if err != nil {
+			return errors.Wrap(err, "compute SlashRoot")
+		}
+		header.SetSlashRoot(root)
+	}
 	return nil
 }
 
@@ -428,7 +462,18 @@ func (e *engineImpl) verifyHeaderSignature(chain engine.ChainReader, header *blo
 			return err
 		}
 	}
+	return verifyHeaderSignatureWithCtx(chain, header, commitSig, commitBitmap, ec)
+}
 
+// verifyHeaderSignatureWithCtx is the epochCtx-independent half of
+// verifyHeaderSignature, split out so a caller that already resolved an
+// epochCtx doesn't pay for a second cache lookup or readEpochCtxFromChain
+// call. verifyHeaderSignature is currently its only caller; the batched path
+// in verify_headers.go goes through VerifyHeaderSignatures instead.
+func verifyHeaderSignatureWithCtx(
+	chain engine.ChainReader, header *block.Header,
+	commitSig bls_cosi.SerializedSignature, commitBitmap []byte, ec *epochCtx,
+) error {
 	var (
 		pubKeys    = ec.pubKeys
 		qrVerifier = ec.qrVerifier
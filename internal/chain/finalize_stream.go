@@ -0,0 +1,138 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/consensus/reward"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/staking/slash"
+	staking "github.com/harmony-one/harmony/staking/types"
+)
+
+// FinalizeBody bundles the per-block inputs Finalize previously took as a long
+// run of individual parameters, so FinalizeAndAssemble's signature doesn't
+// grow every time a new optional piece of block content is added.
+type FinalizeBody struct {
+	Txs           []*types.Transaction
+	Receipts      []*types.Receipt
+	Outgoing      []*types.CXReceipt
+	Incoming      []*types.CXReceiptsProof
+	Staking       staking.StakingTransactions
+	DoubleSigners slash.Records
+}
+
+// SigSource supplies the commit signature and bitmap a block's header should
+// carry once the committee has finished signing the parent block. It replaces
+// the old `sigsReady chan bool` parameter, which coupled the caller's
+// goroutine lifecycle to whichever one happened to be blocked on Finalize:
+// a pipelined proposer, a test, or an HSM-backed external signer can each
+// implement WaitSigs on their own terms, and ctx lets FinalizeAndAssemble's
+// caller cancel the wait instead of leaking a goroutine.
+type SigSource interface {
+	WaitSigs(ctx context.Context) (sig bls_cosi.SerializedSignature, bitmap []byte, err error)
+}
+
+// FinalizeOptions configures a FinalizeAndAssemble call.
+type FinalizeOptions struct {
+	SigSource SigSource
+	ViewID    func() uint64
+}
+
+// chanSigSource adapts the legacy `sigsReady chan bool` signal to a SigSource,
+// so Finalize can keep its old signature as a thin wrapper around
+// FinalizeAndAssemble. It doesn't carry an actual signature/bitmap -- the
+// legacy callers fetch those off the header the same way Finalize always did
+// -- it only gates on ctx/channel the way AccumulateRewardsAndCountSigs
+// expects. A false value (or a closed channel) comes back as an error.
+type chanSigSource struct {
+	sigsReady chan bool
+}
+
+func (c chanSigSource) WaitSigs(ctx context.Context) (bls_cosi.SerializedSignature, []byte, error) {
+	select {
+	case ready := <-c.sigsReady:
+		if !ready {
+			return bls_cosi.SerializedSignature{}, nil, errors.New("commit sigs not ready")
+		}
+		return bls_cosi.SerializedSignature{}, nil, nil
+	case <-ctx.Done():
+		return bls_cosi.SerializedSignature{}, nil, ctx.Err()
+	}
+}
+
+// sigSourceToChan bridges a SigSource back to the chan bool
+// AccumulateRewardsAndCountSigs expects today, until that function is itself
+// made context-aware. It has a one-slot buffer, so the goroutine's single send
+// never blocks even if nothing reads it, and it is closed once WaitSigs
+// returns; the goroutine ends as soon as WaitSigs does.
+func sigSourceToChan(ctx context.Context, src SigSource) chan bool {
+	ready := make(chan bool, 1)
+	go func() {
+		defer close(ready)
+		_, _, err := src.WaitSigs(ctx)
+		ready <- err == nil
+	}()
+	return ready
+}
+
+// FinalizeAndAssemble is the context-aware, pluggable-sig-source replacement
+// for Finalize. Finalize is now a thin wrapper around it; new callers --
+// pipelined proposers, tests with a mock SigSource, HSM-backed external
+// signers -- should call this directly instead.
+func (e *engineImpl) FinalizeAndAssemble(
+	ctx context.Context, chain engine.ChainReader, header *block.Header,
+	state *state.DB, body FinalizeBody, opts FinalizeOptions,
+) (*types.Block, reward.Reader, error) {
+	// finalize() skips AccumulateRewardsAndCountSigs entirely once the merger
+	// reports PoS is finalized, so it never reads the bridged channel in that
+	// case. Don't even start sigSourceToChan's WaitSigs goroutine then --
+	// ctx here is typically context.Background() (Finalize's legacy wrapper),
+	// so with no reader and no cancellation that goroutine would otherwise
+	// block forever once BFT consensus -- and so whatever used to signal
+	// opts.SigSource -- has stopped running post-transition.
+	var sigsReady chan bool
+	if e.merger == nil || !e.merger.PoSFinalized() {
+		sigsReady = sigSourceToChan(ctx, opts.SigSource)
+	} else {
+		sigsReady = make(chan bool)
+	}
+	return e.finalize(
+		chain, header, state,
+		body.Txs, body.Receipts, body.Outgoing, body.Incoming, body.Staking, body.DoubleSigners,
+		sigsReady, opts.ViewID,
+	)
+}
+
+// PreviewRewardPayout computes what Finalize would pay out for header without
+// permanently mutating state, for a builder/proposer that wants to see a
+// candidate block's rewards before deciding to commit it. It runs
+// AccumulateRewardsAndCountSigs against the real state object and then
+// reverts every mutation via state's own snapshot/revert log -- the standard
+// compute-then-discard idiom, and far cheaper than deep-copying the entire
+// state DB the way this used to.
+//
+// Reward computation is not split from state mutation:
+// AccumulateRewardsAndCountSigs still computes and applies the payout in one
+// step, and this method obtains the payout by running it and discarding its
+// state changes.
+//
+// A preview is for a header whose commit sigs are already populated -- there
+// is no committee still signing for it to wait on -- so unlike Finalize this
+// doesn't take a SigSource; it just tells AccumulateRewardsAndCountSigs the
+// signal is already ready.
+func (e *engineImpl) PreviewRewardPayout(
+	chain engine.ChainReader, header *block.Header, state *state.DB,
+) (reward.Reader, error) {
+	snapshot := state.Snapshot()
+	defer state.RevertToSnapshot(snapshot)
+
+	sigsReady := make(chan bool, 1)
+	sigsReady <- true
+	return AccumulateRewardsAndCountSigs(chain, state, header, e.Beaconchain(), sigsReady)
+}
@@ -0,0 +1,116 @@
+package chain
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"github.com/harmony-one/harmony/block"
+)
+
+// TransitionStatus records where a chain is in the PoS-BFT -> external-payload
+// handoff, the harmony analogue of go-ethereum's eth1/eth2 merge TTD handoff.
+// It is small and rarely written, so it's persisted as a single JSON blob
+// rather than a bespoke encoding.
+type TransitionStatus struct {
+	// LeftPoS is set once the chain has produced its terminal BLS-BFT block
+	// and handed block production off to an externally-driven payload engine.
+	LeftPoS bool
+	// Finalized is set once the external payload engine reports the handoff
+	// itself is irreversible, i.e. PoSFinalized() below.
+	Finalized bool
+}
+
+// TransitionStatusStore persists a TransitionStatus across restarts. Callers
+// typically back this with the node's existing chain database.
+type TransitionStatusStore interface {
+	ReadTransitionStatus() []byte
+	WriteTransitionStatus(data []byte)
+}
+
+// Merger tracks the one-way LeavePoS -> ReachedTransition -> FinalizedTransition
+// state machine for a chain that hands off from BLS-BFT to an external payload
+// provider at a configured terminal block. It is meant to be a per-chain
+// singleton, shared between the BeaconEngine and the RPCs that drive it.
+type Merger struct {
+	mu     sync.Mutex
+	store  TransitionStatusStore
+	status TransitionStatus
+}
+
+// NewMerger loads (or initializes) the transition status from store.
+func NewMerger(store TransitionStatusStore) *Merger {
+	m := &Merger{store: store}
+	if raw := store.ReadTransitionStatus(); len(raw) > 0 {
+		_ = json.Unmarshal(raw, &m.status)
+	}
+	return m
+}
+
+// LeavePoS marks the chain as having produced its terminal BLS-BFT block. It
+// is idempotent: calling it again after the chain has already left PoS is a
+// no-op, matching go-ethereum's Merger.ReachTTD.
+func (m *Merger) LeavePoS() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status.LeftPoS {
+		return
+	}
+	m.status.LeftPoS = true
+	m.persist()
+}
+
+// ReachedTransition reports whether the chain has produced its terminal
+// BLS-BFT block and handed off to the external payload engine.
+func (m *Merger) ReachedTransition() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status.LeftPoS
+}
+
+// FinalizeTransition marks the handoff itself as finalized: once set,
+// Finalize stops accumulating BLS-BFT block rewards for good, even across a
+// reorg back before the terminal block.
+func (m *Merger) FinalizeTransition() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status.Finalized {
+		return
+	}
+	m.status.Finalized = true
+	m.persist()
+}
+
+// PoSFinalized reports whether the PoS->external handoff has been finalized.
+func (m *Merger) PoSFinalized() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status.Finalized
+}
+
+func (m *Merger) persist() {
+	raw, err := json.Marshal(m.status)
+	if err != nil {
+		return
+	}
+	m.store.WriteTransitionStatus(raw)
+}
+
+// OverrideTerminalBlockNumberFlagName is the CLI flag node operators use to
+// pin the terminal transition block on testnets without a new binary,
+// analogous to go-ethereum's --override.terminaltotaldifficulty. The flag's
+// value is parsed by cmd/harmony and threaded into the chain config that
+// IsTerminalTransitionBlock is later called with.
+const OverrideTerminalBlockNumberFlagName = "override.terminalblocknumber"
+
+// IsTerminalTransitionBlock reports whether header is the configured terminal
+// block, i.e. the last block to be produced by the BLS-BFT engine before
+// handoff. terminalBlockNumber comes from chain config's
+// (analogous to geth's --override.terminaltotaldifficulty) override flag, so
+// operators can pin the transition on testnets without a new binary.
+func IsTerminalTransitionBlock(header *block.Header, terminalBlockNumber *big.Int) bool {
+	if terminalBlockNumber == nil {
+		return false
+	}
+	return header.Number().Cmp(terminalBlockNumber) == 0
+}
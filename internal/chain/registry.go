@@ -0,0 +1,38 @@
+package chain
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/harmony-one/harmony/consensus/engine"
+)
+
+// EngineType selects which consensus.Engine implementation a chain runs.
+// Shard chains that need real BFT finality use EngineBFT; single-node or
+// small devnet/testnet topologies that don't want to stand up a BLS
+// committee can opt into EngineClique instead.
+type EngineType string
+
+const (
+	// EngineBFT is harmony's native FBFT engine, engineImpl in this package.
+	EngineBFT EngineType = "bft"
+	// EngineClique is a Clique-style proof-of-authority engine, for devnets.
+	EngineClique EngineType = "clique"
+)
+
+// NewEngineByType is the factory entry point for picking a consensus.Engine
+// based on chain config, mirroring how go-ethereum selects between ethash and
+// clique in eth/ethconfig. cliqueConfig is only consulted when engineType is
+// EngineClique.
+func NewEngineByType(engineType EngineType, cliqueConfig *CliqueConfig) (engine.Engine, error) {
+	switch engineType {
+	case "", EngineBFT:
+		return NewEngine(), nil
+	case EngineClique:
+		if cliqueConfig == nil {
+			return nil, errors.New("clique engine requires a CliqueConfig")
+		}
+		return NewCliqueEngine(cliqueConfig), nil
+	default:
+		return nil, errors.Errorf("unknown consensus engine type %q", engineType)
+	}
+}
@@ -0,0 +1,118 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+
+	"github.com/harmony-one/harmony/staking/slash"
+)
+
+// slashGroupKey groups slash.Records that share the same signed-block
+// evidence, so applySlashes can batch one slash.Apply call per event instead
+// of one per offender.
+type slashGroupKey struct {
+	height  uint64
+	viewID  uint64
+	shardID uint32
+	epoch   uint64
+}
+
+// lessSlashGroupKey is a proper tuple order over (shardID, height, viewID,
+// epoch): each field only breaks the tie once every preceding field is equal.
+func lessSlashGroupKey(a, b slashGroupKey) bool {
+	if a.shardID != b.shardID {
+		return a.shardID < b.shardID
+	}
+	if a.height != b.height {
+		return a.height < b.height
+	}
+	if a.viewID != b.viewID {
+		return a.viewID < b.viewID
+	}
+	return a.epoch < b.epoch
+}
+
+// canonicalizeSlashRecords returns a copy of records in one total order:
+// (shardID, height, viewID, epoch), same as lessSlashGroupKey, and then -- to
+// break ties between multiple offenders caught by the same evidence -- by the
+// record's own canonical JSON encoding, which is dominated by the offending
+// validator's BLS public key. applySlashes groups records by
+// slashGroupKey to batch slash.Apply calls, but SlashRoot commits to every
+// individual record, so it needs this fully-resolved order instead.
+func canonicalizeSlashRecords(records slash.Records) (slash.Records, error) {
+	type canonRecord struct {
+		record  slash.Record
+		key     slashGroupKey
+		encoded []byte
+	}
+
+	items := make([]canonRecord, len(records))
+	for i, r := range records {
+		raw, err := json.Marshal(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "encode slash record for canonical ordering")
+		}
+		items[i] = canonRecord{
+			record: r,
+			key: slashGroupKey{
+				height:  r.Evidence.Height,
+				viewID:  r.Evidence.ViewID,
+				shardID: r.Evidence.Moment.ShardID,
+				epoch:   r.Evidence.Moment.Epoch.Uint64(),
+			},
+			encoded: raw,
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].key != items[j].key {
+			return lessSlashGroupKey(items[i].key, items[j].key)
+		}
+		return bytes.Compare(items[i].encoded, items[j].encoded) < 0
+	})
+
+	canon := make(slash.Records, len(items))
+	for i, item := range items {
+		canon[i] = item.record
+	}
+	return canon, nil
+}
+
+// computeSlashRoot commits to canonicalized slashes with a simple binary
+// Merkle tree over keccak256(json(record)) leaves, the same shape Ethereum
+// headers use to commit to receipts and withdrawals roots, so a verifier can
+// check one slash.Record's inclusion without replaying state.
+func computeSlashRoot(canon slash.Records) (common.Hash, error) {
+	if len(canon) == 0 {
+		return common.Hash{}, nil
+	}
+
+	level := make([]common.Hash, len(canon))
+	for i, r := range canon {
+		raw, err := json.Marshal(r)
+		if err != nil {
+			return common.Hash{}, errors.Wrap(err, "encode slash record for merkle leaf")
+		}
+		level[i] = crypto.Keccak256Hash(raw)
+	}
+
+	for len(level) > 1 {
+		next := make([]common.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				// Odd node out: duplicate it, the usual convention for
+				// binary Merkle trees with an unbalanced leaf count.
+				next = append(next, crypto.Keccak256Hash(level[i].Bytes(), level[i].Bytes()))
+				continue
+			}
+			next = append(next, crypto.Keccak256Hash(level[i].Bytes(), level[i+1].Bytes()))
+		}
+		level = next
+	}
+	return level[0], nil
+}
@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"sort"
+	"testing"
+)
+
+// FuzzSlashGroupKeyOrder proves lessSlashGroupKey defines a total order: for
+// any two keys, exactly one of less(a,b)/less(b,a) holds unless they're
+// equal, and sorting the same three-key multiset twice always yields the same order
+// (the property the buggy chained else-ifs violated: a's shardID alone could
+// decide the order, so comparisons had to stop there instead of falling
+// through to height/viewID/epoch).
+func FuzzSlashGroupKeyOrder(f *testing.F) {
+	f.Add(uint64(1), uint64(1), uint32(1), uint64(1), uint64(2), uint64(2), uint32(1), uint64(1))
+	f.Fuzz(func(t *testing.T, h1, v1 uint64, s1 uint32, e1, h2, v2 uint64, s2 uint32, e2 uint64) {
+		a := slashGroupKey{height: h1, viewID: v1, shardID: s1, epoch: e1}
+		b := slashGroupKey{height: h2, viewID: v2, shardID: s2, epoch: e2}
+
+		lessAB, lessBA := lessSlashGroupKey(a, b), lessSlashGroupKey(b, a)
+		if lessAB && lessBA {
+			t.Fatalf("antisymmetry violated: less(a,b) and less(b,a) both true for a=%+v b=%+v", a, b)
+		}
+		if a == b && (lessAB || lessBA) {
+			t.Fatalf("irreflexivity violated: equal keys compared unequal, a=%+v b=%+v", a, b)
+		}
+		if a != b && !lessAB && !lessBA {
+			t.Fatalf("totality violated: neither a<b nor b<a for distinct a=%+v b=%+v", a, b)
+		}
+
+		keys := []slashGroupKey{a, b, {height: h1, viewID: v2, shardID: s2, epoch: e1}}
+		first := append([]slashGroupKey{}, keys...)
+		second := append([]slashGroupKey{}, keys...)
+		sort.SliceStable(first, func(i, j int) bool { return lessSlashGroupKey(first[i], first[j]) })
+		sort.SliceStable(second, func(i, j int) bool { return lessSlashGroupKey(second[i], second[j]) })
+		for i := range first {
+			if first[i] != second[i] {
+				t.Fatalf("sort is not stable/deterministic across repeated runs: %+v vs %+v", first, second)
+			}
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package chain
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/consensus/engine"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+)
+
+// verifyHeadersConcurrent verifies a batch of headers in parallel across a
+// pool of GOMAXPROCS workers. Every header, sealed or not, is first resolved
+// to its parent -- falling back to the batch itself when a parent hasn't been
+// written to the chain yet, so the downloader/sync path can verify a long
+// range of headers before committing any of them -- matching VerifyHeader,
+// which checks ancestry regardless of seal. Sealed headers (once the chain is
+// past its bootstrap window, see VerifySeal) are then split into GOMAXPROCS
+// contiguous chunks. Each worker hands its chunk to VerifyHeaderSignatures,
+// which collapses every signature check in the chunk into one multi-pairing
+// call instead of one pairing check per header.
+func (e *engineImpl) verifyHeadersConcurrent(
+	chain engine.ChainReader, headers []*block.Header, seals []bool,
+) (chan<- struct{}, <-chan error) {
+	abort, results := make(chan struct{}), make(chan error, len(headers))
+	if len(headers) == 0 {
+		close(results)
+		return abort, results
+	}
+
+	headersByHash := make(map[common.Hash]*block.Header, len(headers))
+	for _, header := range headers {
+		headersByHash[header.Hash()] = header
+	}
+
+	errs := make([]error, len(headers))
+	sealedIdx := make([]int, 0, len(headers))
+	parents := make([]*block.Header, len(headers))
+	sigs := make([]bls_cosi.SerializedSignature, len(headers))
+	bitmaps := make([][]byte, len(headers))
+
+	// VerifySeal special-cases a chain that hasn't produced its second block
+	// yet: there's no committee to check a signature against, so skip sig
+	// verification entirely. Ancestor resolution below still applies to every
+	// header regardless of seal, matching VerifyHeader.
+	bootstrapping := chain.CurrentHeader().Number().Uint64() <= uint64(1)
+
+	for i, header := range headers {
+		parent := chain.GetHeader(header.ParentHash(), header.Number().Uint64()-1)
+		if parent == nil {
+			parent = headersByHash[header.ParentHash()]
+		}
+		if parent == nil {
+			errs[i] = engine.ErrUnknownAncestor
+			continue
+		}
+		if !seals[i] || bootstrapping {
+			continue
+		}
+		parents[i] = parent
+		sigs[i] = header.LastCommitSignature()
+		bitmaps[i] = header.LastCommitBitmap()
+		sealedIdx = append(sealedIdx, i)
+	}
+
+	go func() {
+		defer close(results)
+
+		if len(sealedIdx) > 0 {
+			numWorkers := runtime.GOMAXPROCS(0)
+			if numWorkers > len(sealedIdx) {
+				numWorkers = len(sealedIdx)
+			}
+			if numWorkers < 1 {
+				numWorkers = 1
+			}
+			chunkSize := (len(sealedIdx) + numWorkers - 1) / numWorkers
+
+			var wg sync.WaitGroup
+			for start := 0; start < len(sealedIdx); start += chunkSize {
+				end := start + chunkSize
+				if end > len(sealedIdx) {
+					end = len(sealedIdx)
+				}
+				chunk := sealedIdx[start:end]
+
+				wg.Add(1)
+				go func(chunk []int) {
+					defer wg.Done()
+
+					chunkParents := make([]*block.Header, len(chunk))
+					chunkSigs := make([]bls_cosi.SerializedSignature, len(chunk))
+					chunkBitmaps := make([][]byte, len(chunk))
+					for j, i := range chunk {
+						chunkParents[j], chunkSigs[j], chunkBitmaps[j] = parents[i], sigs[i], bitmaps[i]
+					}
+
+					chunkErrs := e.VerifyHeaderSignatures(chain, chunkParents, chunkSigs, chunkBitmaps)
+					for j, i := range chunk {
+						errs[i] = chunkErrs[j]
+					}
+				}(chunk)
+			}
+			wg.Wait()
+		}
+
+		for i := range headers {
+			select {
+			case <-abort:
+				return
+			case results <- errs[i]:
+			}
+		}
+	}()
+
+	return abort, results
+}
@@ -0,0 +1,30 @@
+package params
+
+import "math/big"
+
+// ChainConfig carries every fork's activation epoch (StakingEpoch, S3Epoch,
+// NoEarlyUnlockEpoch, ...); config.go (not part of this change) defines the
+// rest of them, and this file only adds the one field and predicate the
+// SlashRoot fork needs.
+type ChainConfig struct {
+	// SlashRootEpoch is the epoch at which headers start committing a Merkle
+	// root of their applied slashes (see internal/chain's applySlashes). Nil
+	// means the fork isn't configured, so IsSlashRoot is always false --
+	// the same "unset epoch = never active" convention the config's other
+	// optional forks already use.
+	SlashRootEpoch *big.Int
+}
+
+// IsSlashRoot reports whether epoch is at or after the configured
+// SlashRootEpoch, the same isForked convention as the config's other IsX
+// fork predicates.
+func (c *ChainConfig) IsSlashRoot(epoch *big.Int) bool {
+	return isForked(c.SlashRootEpoch, epoch)
+}
+
+func isForked(forkEpoch, epoch *big.Int) bool {
+	if forkEpoch == nil || epoch == nil {
+		return false
+	}
+	return epoch.Cmp(forkEpoch) >= 0
+}